@@ -6,7 +6,9 @@ import (
 	"encoding/json"
 	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
+	"strconv"
 	"strings"
 	"time"
 
@@ -15,6 +17,12 @@ import (
 
 const (
 	apiBaseURL = "https://napi.arvancloud.ir/cdn/4.0"
+
+	defaultUserAgent = "libdns-arvancloud"
+
+	maxRetryAttempts = 5
+	baseRetryBackoff = time.Second
+	maxRetryBackoff  = 30 * time.Second
 )
 
 // client manages communication with the ArvanCloud API.
@@ -22,35 +30,29 @@ type client struct {
 	AuthAPIKey string
 	BaseURL      string
 	httpClient   *http.Client
+	userAgent    string
 }
 
-// newClient creates a new ArvanCloud API client.
-func newClient(authKey string) *client {
+// newClient creates a new ArvanCloud API client. If httpClient is nil, a
+// client with a sane default timeout is used; if userAgent is empty,
+// defaultUserAgent is sent instead.
+func newClient(authKey string, httpClient *http.Client, userAgent string) *client {
+	if httpClient == nil {
+		httpClient = &http.Client{
+			Timeout: time.Second * 20,
+		}
+	}
+	if userAgent == "" {
+		userAgent = defaultUserAgent
+	}
 	return &client{
 		AuthAPIKey: authKey,
 		BaseURL:      apiBaseURL,
-		httpClient: &http.Client{
-			Timeout: time.Second * 20,
-		},
+		httpClient: httpClient,
+		userAgent:  userAgent,
 	}
 }
 
-type paginatedResponse struct {
-	Data  []arDNSRecord `json:"data"`
-	Links struct {
-		Next *string `json:"next"`
-	} `json:"links"`
-	Meta struct {
-		CurrentPage int `json:"current_page"`
-		LastPage    int `json:"last_page"`
-	} `json:"meta"`
-}
-
-type singleRecordResponse struct {
-	Data    arDNSRecord `json:"data"`
-	Message *string   `json:"message"`
-}
-
 // getRecords fetches DNS records for a zone.
 func (c *client) getRecords(ctx context.Context, zone string) ([]arDNSRecord, error) {
 	var records []arDNSRecord
@@ -63,14 +65,15 @@ func (c *client) getRecords(ctx context.Context, zone string) ([]arDNSRecord, er
 			return nil, err
 		}
 
-		var resp paginatedResponse
-		if _,err := c.do(req, &resp); err != nil {
+		var recs []arDNSRecord
+		resp, err := c.do(req, &recs)
+		if err != nil {
 			return nil, err
 		}
 
-		records = append(records, resp.Data...)
+		records = append(records, recs...)
 
-		if resp.Links.Next == nil || resp.Meta.CurrentPage >= resp.Meta.LastPage {
+		if resp.Meta == nil || resp.Meta.CurrentPage >= resp.Meta.LastPage {
 			break
 		}
 		page++
@@ -80,27 +83,72 @@ func (c *client) getRecords(ctx context.Context, zone string) ([]arDNSRecord, er
 }
 
 
-func (p *Provider) findExistingRecord(records []arDNSRecord, name, rType, zone string) *arDNSRecord {
-	searchName := libdns.AbsoluteName(name, zone)
-	for i, r := range records {
-		// Arvan name usually comes as "sub" or "sub.domain.com" depending on context,
-		// but getRecords usually returns full name or relative?
-		// The spec says "name" in response.
-		// Let's assume absolute name matching or relative matching.
-		// Safest is to compare both normalized absolute names.
-
-		recordName := r.Name
-		if !strings.Contains(recordName, zone) && recordName != "@" {
-			// If record name is relative, make it absolute for comparison
-			if recordName == "@" {
-				recordName = zone
-			} else {
-				recordName = recordName + "." + zone
+// findHostedZone discovers the ArvanCloud-registered domain that fqdn
+// belongs to by paginating through GET /domains and returning the longest
+// registered Domain that is a suffix of fqdn. This lets callers address
+// records by any FQDN under a zone without knowing the exact registered
+// domain name up front.
+func (c *client) findHostedZone(ctx context.Context, fqdn string) (string, error) {
+	fqdn = strings.ToLower(strings.TrimSuffix(fqdn, "."))
+	page := 1
+	var best string
+
+	for {
+		u := fmt.Sprintf("/domains?page=%d&per_page=100", page)
+		req, err := c.newRequest(ctx, http.MethodGet, u, nil)
+		if err != nil {
+			return "", err
+		}
+
+		var domains []arDomain
+		resp, err := c.do(req, &domains)
+		if err != nil {
+			return "", err
+		}
+
+		for _, d := range domains {
+			domain := strings.ToLower(strings.TrimSuffix(d.Domain, "."))
+			if domain == "" {
+				continue
+			}
+			if domain != fqdn && !strings.HasSuffix(fqdn, "."+domain) {
+				continue
+			}
+			if len(domain) > len(best) {
+				best = domain
 			}
 		}
-		recordName = strings.TrimSuffix(recordName, ".")
 
-		if strings.EqualFold(recordName, searchName) && strings.EqualFold(r.Type, rType) {
+		if resp.Meta == nil || resp.Meta.CurrentPage >= resp.Meta.LastPage {
+			break
+		}
+		page++
+	}
+
+	if best == "" {
+		return "", fmt.Errorf("no hosted zone found for %q", fqdn)
+	}
+
+	return best, nil
+}
+
+// arvanAbsoluteName normalizes an Arvan API record name - which may come
+// back as "@", a bare relative label, or an already-absolute name - into
+// an absolute FQDN comparable against libdns.AbsoluteName(name, zone).
+func arvanAbsoluteName(recordName, zone string) string {
+	if recordName == "@" {
+		return strings.TrimSuffix(zone, ".")
+	}
+	if !strings.Contains(recordName, zone) {
+		recordName = recordName + "." + zone
+	}
+	return strings.TrimSuffix(recordName, ".")
+}
+
+func (p *Provider) findExistingRecord(records []arDNSRecord, name, rType, zone string) *arDNSRecord {
+	searchName := libdns.AbsoluteName(name, zone)
+	for i, r := range records {
+		if strings.EqualFold(arvanAbsoluteName(r.Name, zone), searchName) && strings.EqualFold(r.Type, rType) {
 			return &records[i]
 		}
 	}
@@ -108,25 +156,25 @@ func (p *Provider) findExistingRecord(records []arDNSRecord, name, rType, zone s
 }
 // createRecord creates a new DNS record.
 func (c *client) createRecord(ctx context.Context, zone string, record libdns.Record) (arDNSRecord, error) {
-
 	arRec, err := arvancloudRecord(record)
 	if err != nil {
 		return arDNSRecord{}, err
 	}
+	return c.createArvanRecord(ctx, zone, arRec)
+}
 
-	jsonBytes, err := json.Marshal(arRec)
-	if err != nil {
-		return arDNSRecord{}, err
-	}
-
+// createArvanRecord creates arRec as-is, without deriving it from a
+// libdns.Record first. Used when a caller has already built the combined
+// arDNSRecord it wants to send (e.g. a coalesced multi-value A/AAAA record).
+func (c *client) createArvanRecord(ctx context.Context, zone string, arRec arDNSRecord) (arDNSRecord, error) {
 	u := fmt.Sprintf("/domains/%s/dns-records", zone)
-	req, err := c.newRequest(ctx, http.MethodPost, u, bytes.NewReader(jsonBytes))
+	req, err := c.newRequest(ctx, http.MethodPost, u, arRec)
 	if err != nil {
 		return arDNSRecord{}, err
 	}
 
 	var resp arDNSRecord
-	if _,err := c.do(req, &resp); err != nil {
+	if _, err := c.do(req, &resp); err != nil {
 		return arDNSRecord{}, err
 	}
 
@@ -157,45 +205,121 @@ func (c *client) updateRecord(ctx context.Context, zone string, recordID string,
 		return arDNSRecord{}, err
 	}
 
-	var resp singleRecordResponse
-	if _,err := c.do(req, &resp); err != nil {
+	var resp arDNSRecord
+	if _, err := c.do(req, &resp); err != nil {
 		return arDNSRecord{}, err
 	}
 
-	return resp.Data, nil
+	return resp, nil
 }
 
-func (c *client) do(req *http.Request, result any) (arResponse,error) {
-	resp, err := c.httpClient.Do(req)
-	if err != nil {
-		return arResponse{}, err
-	}
-	defer resp.Body.Close()
+// do executes req, retrying on HTTP 429 (honoring Retry-After) and 5xx
+// responses with exponential backoff and jitter, up to maxRetryAttempts.
+// The body is buffered once per attempt so a decode failure still leaves
+// the raw bytes available for the returned error.
+func (c *client) do(req *http.Request, result any) (arResponse, error) {
+	var lastErr error
+
+	for attempt := 0; ; attempt++ {
+		if attempt > 0 {
+			if req.GetBody == nil {
+				return arResponse{}, lastErr
+			}
+			body, err := req.GetBody()
+			if err != nil {
+				return arResponse{}, err
+			}
+			req.Body = body
+		}
 
+		resp, err := c.httpClient.Do(req)
+		if err != nil {
+			return arResponse{}, err
+		}
 
-	var respData arResponse
+		body, err := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			return arResponse{}, err
+		}
 
-	err = json.NewDecoder(resp.Body).Decode(&respData)
-	if err != nil {
-		return arResponse{}, err
-	}
-	
-	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		return arResponse{}, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			lastErr = fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+			if attempt >= maxRetryAttempts-1 {
+				return arResponse{}, lastErr
+			}
+			delay := retryBackoff(attempt)
+			if resp.StatusCode == http.StatusTooManyRequests {
+				if ra := parseRetryAfter(resp.Header.Get("Retry-After")); ra > 0 {
+					delay = ra
+				}
+			}
+			if !sleepOrDone(req.Context(), delay) {
+				return arResponse{}, lastErr
+			}
+			continue
+		}
+
+		var respData arResponse
+		if err := json.Unmarshal(body, &respData); err != nil {
+			return arResponse{}, fmt.Errorf("decoding response (HTTP %d, body %q): %w", resp.StatusCode, string(body), err)
+		}
+
+		if resp.StatusCode >= 400 {
+			return arResponse{}, fmt.Errorf("API error %d: %s", resp.StatusCode, string(body))
+		}
+
+		if len(respData.Errors) > 0 {
+			return arResponse{}, fmt.Errorf("got errors: HTTP %d: %+v", resp.StatusCode, respData.Errors)
+		}
+
+		if len(respData.Data) > 0 && result != nil {
+			if err := json.Unmarshal(respData.Data, result); err != nil {
+				return arResponse{}, err
+			}
+		}
+		return respData, nil
 	}
+}
 
-	if len(respData.Errors) > 0 {
-		return arResponse{}, fmt.Errorf("got errors: HTTP %d: %+v", resp.StatusCode, respData.Errors)
+// retryBackoff returns the delay before retry attempt n (0-indexed),
+// doubling from baseRetryBackoff and capped at maxRetryBackoff, with up to
+// 50% jitter added to avoid thundering-herd retries.
+func retryBackoff(attempt int) time.Duration {
+	d := baseRetryBackoff << attempt
+	if d > maxRetryBackoff || d <= 0 {
+		d = maxRetryBackoff
 	}
+	return d + time.Duration(rand.Int63n(int64(d)/2+1))
+}
 
-	if len(respData.Data) > 0 && result != nil {
-		err = json.Unmarshal(respData.Data, result)
-		if err != nil {
-			return arResponse{}, err
+// parseRetryAfter parses a Retry-After header value (either delay-seconds
+// or an HTTP-date) into a duration, returning 0 if it can't be parsed.
+func parseRetryAfter(v string) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
 		}
 	}
-	return respData ,nil
+	return 0
+}
+
+// sleepOrDone waits for d, returning false early if ctx is done first.
+func sleepOrDone(ctx context.Context, d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return false
+	case <-timer.C:
+		return true
+	}
 }
 
 func (c *client) newRequest(ctx context.Context, method, url string, payload any) (*http.Request, error) {
@@ -216,6 +340,7 @@ func (c *client) newRequest(ctx context.Context, method, url string, payload any
 
 	req.Header.Set("Authorization", "Apikey "+c.AuthAPIKey)
 	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", c.userAgent)
 	if payload != nil {
 		req.Header.Set("Content-Type", "application/json")
 	}
@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/netip"
+	"strconv"
 	"strings"
 	"time"
 	"github.com/libdns/libdns"
@@ -40,15 +41,36 @@ type arDomain struct {
 }
 
 type arDNSRecord struct {
-	ID            string      `json:"id,omitempty"`
-	Type          string      `json:"type"`
-	Name          string      `json:"name"`
-	Value         any 		  `json:"value"` 
-	TTL           int         `json:"ttl"`
-	Cloud         bool        `json:"cloud"`
-	IsProtected	  bool 		  `json:"is_protected,omitempty"`
-	UpstreamHTTPS string      `json:"upstream_https,omitempty"`
-	IPFilterMode  *IPFilter   `json:"ip_filter_mode,omitempty"`
+	ID            string          `json:"id,omitempty"`
+	Type          string          `json:"type"`
+	Name          string          `json:"name"`
+	Value         json.RawMessage `json:"value"`
+	TTL           int             `json:"ttl"`
+	Cloud         bool            `json:"cloud"`
+	IsProtected	  bool 		      `json:"is_protected,omitempty"`
+	UpstreamHTTPS string          `json:"upstream_https,omitempty"`
+	IPFilterMode  *IPFilter       `json:"ip_filter_mode,omitempty"`
+}
+
+// decodeValue unmarshals a record's raw API value into a typed destination.
+// arDNSRecord.Value is kept as json.RawMessage (rather than a pre-asserted
+// type) because the API's "value" shape depends on the sibling "type"
+// field, which plain json.Unmarshal-into-any can't express.
+func decodeValue(raw json.RawMessage, dst any) error {
+	if len(raw) == 0 {
+		return nil
+	}
+	return json.Unmarshal(raw, dst)
+}
+
+// encodeValue marshals a typed record value into the raw form the API
+// expects for the "value" field.
+func encodeValue(v any) (json.RawMessage, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return json.RawMessage(b), nil
 }
 
 // IPFilter defines the IP filtering mode for a record.
@@ -101,6 +123,7 @@ type SRVRecordValue struct {
 
 // CAARecordValue represents the value structure for CAA records.
 type CAARecordValue struct {
+	Flags uint8  `json:"flags"`
 	Value string `json:"value"`
 	Tag   string `json:"tag"`
 }
@@ -123,82 +146,307 @@ type TLSARecordValue struct {
 	Certificate  string `json:"certificate"`
 }
 
-func (r arDNSRecord) toLibDNSRecord(zone string) (libdns.Record, error) {
+// toLibDNSRecords converts an Arvan record into one or more libdns.Record
+// values. Most types produce exactly one record; A/AAAA records carry a
+// slice of values (ArvanCloud's multi-IP/geo-targeting shape), so they
+// expand into one address record per IP (wrapped in Record when the Arvan
+// record carries cloud, upstream HTTPS or IP filter settings). Values with
+// port, weight or country data instead produce a single Record carrying
+// every value in GeoTargets.
+func (r arDNSRecord) toLibDNSRecords(zone string) ([]libdns.Record, error) {
 	name := libdns.RelativeName(r.Name, zone)
 	ttl := time.Duration(r.TTL) * time.Second
+
+	extend := func(rec libdns.Record) libdns.Record {
+		if r.Cloud || r.UpstreamHTTPS != "" || r.IPFilterMode != nil {
+			return Record{
+				Record:        rec,
+				Proxied:       r.Cloud,
+				UpstreamHTTPS: r.UpstreamHTTPS,
+				IPFilter:      r.IPFilterMode,
+			}
+		}
+		return rec
+	}
+
 	switch r.Type {
 	case "A", "AAAA":
-		addr, err := netip.ParseAddr(r.Value.(ARecordValue).IP)
-		if err != nil {
-			return libdns.Address{}, fmt.Errorf("invalid IP address %q: %v", r.Value.(ARecordValue).IP, err)
+		var vals []ARecordValue
+		if err := decodeValue(r.Value, &vals); err != nil {
+			return nil, fmt.Errorf("decoding %s value for %q: %w", r.Type, r.Name, err)
 		}
-		return libdns.Address{
-			Name: name,
-			TTL:  ttl,
-			IP:   addr,
-		}, nil
+		if len(vals) == 0 {
+			return nil, fmt.Errorf("%s record %q has no values", r.Type, r.Name)
+		}
+
+		hasGeoData := false
+		for _, v := range vals {
+			if v.Port != 0 || v.Weight != 0 || v.Country != "" {
+				hasGeoData = true
+				break
+			}
+		}
+
+		// Geo-targeted values can't be split across several libdns.Address
+		// records (each IP's weight/port/country would be lost), so they're
+		// carried as a single Record whose GeoTargets overrides its one
+		// embedded IP with the full set, per Record.GeoTargets' contract.
+		if hasGeoData {
+			addr, err := netip.ParseAddr(vals[0].IP)
+			if err != nil {
+				return nil, fmt.Errorf("invalid IP address %q: %v", vals[0].IP, err)
+			}
+			rec := Record{
+				Record: libdns.Address{
+					Name: name,
+					TTL:  ttl,
+					IP:   addr,
+				},
+				Proxied:       r.Cloud,
+				UpstreamHTTPS: r.UpstreamHTTPS,
+				IPFilter:      r.IPFilterMode,
+				GeoTargets:    vals,
+			}
+			return []libdns.Record{rec}, nil
+		}
+
+		records := make([]libdns.Record, 0, len(vals))
+		for _, v := range vals {
+			addr, err := netip.ParseAddr(v.IP)
+			if err != nil {
+				return nil, fmt.Errorf("invalid IP address %q: %v", v.IP, err)
+			}
+			rec := libdns.Record(libdns.Address{
+				Name: name,
+				TTL:  ttl,
+				IP:   addr,
+			})
+			if r.Cloud || r.UpstreamHTTPS != "" || r.IPFilterMode != nil {
+				rec = Record{
+					Record:        rec,
+					Proxied:       r.Cloud,
+					UpstreamHTTPS: r.UpstreamHTTPS,
+					IPFilter:      r.IPFilterMode,
+				}
+			}
+			records = append(records, rec)
+		}
+		return records, nil
+	}
+
+	rec, err := r.toSingleLibDNSRecord(name, ttl)
+	if err != nil {
+		return nil, err
+	}
+	return []libdns.Record{extend(rec)}, nil
+}
+
+// toLibDNSRecord converts an Arvan record into a single libdns.Record,
+// taking the first record toLibDNSRecords returns: for a multi-valued
+// A/AAAA record without geo data that is only its first IP. Use
+// toLibDNSRecords when listing a whole zone so multi-value records aren't
+// silently truncated.
+func (r arDNSRecord) toLibDNSRecord(zone string) (libdns.Record, error) {
+	records, err := r.toLibDNSRecords(zone)
+	if err != nil {
+		return nil, err
+	}
+	return records[0], nil
+}
+
+func (r arDNSRecord) toSingleLibDNSRecord(name string, ttl time.Duration) (libdns.Record, error) {
+	switch r.Type {
 	case "CAA":
+		var val CAARecordValue
+		if err := decodeValue(r.Value, &val); err != nil {
+			return nil, fmt.Errorf("decoding CAA value for %q: %w", r.Name, err)
+		}
 		return libdns.CAA{
 			Name:  name,
 			TTL:   ttl,
-			Tag:   r.Value.(CAARecordValue).Tag,
-			Value: r.Value.(CAARecordValue).Value,
+			Flags: val.Flags,
+			Tag:   val.Tag,
+			Value: val.Value,
 		}, nil
 	case "CNAME":
+		var val CNAMERecordValue
+		if err := decodeValue(r.Value, &val); err != nil {
+			return nil, fmt.Errorf("decoding CNAME value for %q: %w", r.Name, err)
+		}
 		return libdns.CNAME{
 			Name:   name,
 			TTL:    ttl,
-			Target: r.Value.(CNAMERecordValue).Host,
+			Target: val.Host,
 		}, nil
 	case "MX":
+		var val MXRecordValue
+		if err := decodeValue(r.Value, &val); err != nil {
+			return nil, fmt.Errorf("decoding MX value for %q: %w", r.Name, err)
+		}
 		return libdns.MX{
 			Name:       name,
 			TTL:        ttl,
-			Preference: r.Value.(MXRecordValue).Priority,
-			Target:     r.Value.(MXRecordValue).Host,
+			Preference: val.Priority,
+			Target:     val.Host,
 		}, nil
 	case "NS":
+		var val NSRecordValue
+		if err := decodeValue(r.Value, &val); err != nil {
+			return nil, fmt.Errorf("decoding NS value for %q: %w", r.Name, err)
+		}
 		return libdns.NS{
 			Name:   name,
 			TTL:    ttl,
-			Target: r.Value.(NSRecordValue).Host,
+			Target: val.Host,
 		}, nil
-	case "SRV":		
-		return  libdns.SRV{
-			Name: name,
-			TTL:  ttl,
-			Priority: r.Value.(SRVRecordValue).Priority,
-			Weight:   r.Value.(SRVRecordValue).Weight,
-			Port:     r.Value.(SRVRecordValue).Port,
-			Target:   r.Value.(SRVRecordValue).Target,
+	case "SRV":
+		var val SRVRecordValue
+		if err := decodeValue(r.Value, &val); err != nil {
+			return nil, fmt.Errorf("decoding SRV value for %q: %w", r.Name, err)
+		}
+		return libdns.SRV{
+			Name:     name,
+			TTL:      ttl,
+			Priority: val.Priority,
+			Weight:   val.Weight,
+			Port:     val.Port,
+			Target:   val.Target,
 		}, nil
 	case "TXT":
-		// unwrap the quotes from the content
-		unwrappedContent := unwrapContent(r.Value.(TXTRecordValue).Text)
+		var val TXTRecordValue
+		if err := decodeValue(r.Value, &val); err != nil {
+			return nil, fmt.Errorf("decoding TXT value for %q: %w", r.Name, err)
+		}
 		return libdns.TXT{
 			Name: name,
 			TTL:  ttl,
-			Text: unwrappedContent,
+			Text: unwrapContent(val.Text),
+		}, nil
+	case "ANAME":
+		var val ANAMERecordValue
+		if err := decodeValue(r.Value, &val); err != nil {
+			return nil, fmt.Errorf("decoding ANAME value for %q: %w", r.Name, err)
+		}
+		return libdns.RR{
+			Name: name,
+			TTL:  ttl,
+			Type: r.Type,
+			Data: anameData(val),
+		}, nil
+	case "PTR":
+		var val PTRRecordValue
+		if err := decodeValue(r.Value, &val); err != nil {
+			return nil, fmt.Errorf("decoding PTR value for %q: %w", r.Name, err)
+		}
+		return libdns.RR{
+			Name: name,
+			TTL:  ttl,
+			Type: r.Type,
+			Data: val.Domain,
+		}, nil
+	case "TLSA":
+		var val TLSARecordValue
+		if err := decodeValue(r.Value, &val); err != nil {
+			return nil, fmt.Errorf("decoding TLSA value for %q: %w", r.Name, err)
+		}
+		return libdns.RR{
+			Name: name,
+			TTL:  ttl,
+			Type: r.Type,
+			Data: tlsaData(val),
 		}, nil
-	// 	fallthrough
 	default:
 		var fields map[string]any
-		json.Unmarshal([]byte(r.Value.(string)), &fields)
+		if err := decodeValue(r.Value, &fields); err != nil {
+			return nil, fmt.Errorf("decoding %s value for %q: %w", r.Type, r.Name, err)
+		}
 		var vals []string
 		for _, v := range fields {
-				vals = append(vals, fmt.Sprintf("%v", v))
+			vals = append(vals, fmt.Sprintf("%v", v))
 		}
 		return libdns.RR{
 			Name: name,
 			TTL:  ttl,
 			Type: r.Type,
-			Data: strings.Join(vals," "),
+			Data: strings.Join(vals, " "),
 		}.Parse()
 	}
 }
 
+// anameData serializes an ANAMERecordValue into the space-separated form
+// libdns.RR.Data expects, omitting trailing fields that are unset.
+func anameData(val ANAMERecordValue) string {
+	data := val.Location
+	if val.HostHeader != "" {
+		data += " " + val.HostHeader
+	}
+	if val.Port != 0 {
+		data += fmt.Sprintf(" %d", val.Port)
+	}
+	return data
+}
+
+// parseANAMEData parses the space-separated form anameData produces back
+// into an ANAMERecordValue. The trailing field is treated as Port when
+// it parses as a number, and any fields between Location and Port are
+// treated as HostHeader.
+func parseANAMEData(data string) ANAMERecordValue {
+	fields := strings.Fields(data)
+	var val ANAMERecordValue
+	if len(fields) == 0 {
+		return val
+	}
+	val.Location = fields[0]
+	rest := fields[1:]
+	if len(rest) > 0 {
+		if port, err := strconv.Atoi(rest[len(rest)-1]); err == nil {
+			val.Port = port
+			rest = rest[:len(rest)-1]
+		}
+	}
+	if len(rest) > 0 {
+		val.HostHeader = strings.Join(rest, " ")
+	}
+	return val
+}
+
+// tlsaData serializes a TLSARecordValue into the space-separated
+// usage/selector/matching-type/certificate form libdns.RR.Data expects,
+// matching TLSA's DNS presentation format.
+func tlsaData(val TLSARecordValue) string {
+	return strings.Join([]string{val.Usage, val.Selector, val.MatchingType, val.Certificate}, " ")
+}
+
+// parseTLSAData parses the space-separated form tlsaData produces back
+// into a TLSARecordValue.
+func parseTLSAData(data string) TLSARecordValue {
+	fields := strings.SplitN(data, " ", 4)
+	var val TLSARecordValue
+	if len(fields) > 0 {
+		val.Usage = fields[0]
+	}
+	if len(fields) > 1 {
+		val.Selector = fields[1]
+	}
+	if len(fields) > 2 {
+		val.MatchingType = fields[2]
+	}
+	if len(fields) > 3 {
+		val.Certificate = fields[3]
+	}
+	return val
+}
+
 func arvancloudRecord(r libdns.Record) (arDNSRecord, error) {
 
+	// Unwrap ArvanCloud-specific extensions, if any, before inspecting the
+	// concrete record type below.
+	var ext *Record
+	if wrapped, ok := r.(Record); ok {
+		ext = &wrapped
+		r = wrapped.Record
+	}
+
 	rr := r.RR()
 	arRec := arDNSRecord{
 		// ID:   r.ID,
@@ -206,41 +454,83 @@ func arvancloudRecord(r libdns.Record) (arDNSRecord, error) {
 		Type:    rr.Type,
 		TTL:     int(rr.TTL.Seconds()),
 	}
+
+	var value any
 	switch rec := r.(type) {
 	case libdns.Address:
-		arRec.Value = ARecordValue{
+		value = ARecordValue{
 			IP: rec.IP.String(),
 		}
 	case libdns.CNAME:
-		arRec.Value = CNAMERecordValue{
+		value = CNAMERecordValue{
 			Host: rec.Target,
 		}
 	case libdns.NS:
-		arRec.Value = NSRecordValue{
+		value = NSRecordValue{
 			Host: rec.Target,
 		}
 	case libdns.CAA:
-		arRec.Value = CAARecordValue{
+		value = CAARecordValue{
+			Flags: rec.Flags,
 			Tag:   rec.Tag,
 			Value: rec.Value,
 		}
 	case libdns.MX:
-		arRec.Value = MXRecordValue{
+		value = MXRecordValue{
 			Priority: rec.Preference,
 			Host: rec.Target,
 		}
 	case libdns.SRV:
-		arRec.Value = SRVRecordValue{
+		value = SRVRecordValue{
 			Priority: rec.Priority,
 			Weight:   rec.Weight,
 			Port:     rec.Port,
 			Target:   rec.Target,
 		}
 	case libdns.TXT:
-		arRec.Value = TXTRecordValue{
+		value = TXTRecordValue{
 			Text: wrapContent(rec.Text),
-		}		
+		}
+	default:
+		switch rr.Type {
+		case "ANAME":
+			value = parseANAMEData(rr.Data)
+		case "PTR":
+			value = PTRRecordValue{Domain: rr.Data}
+		case "TLSA":
+			value = parseTLSAData(rr.Data)
+		default:
+			value = rr.Data
+		}
+	}
+
+	if ext != nil {
+		arRec.Cloud = ext.Proxied
+		arRec.UpstreamHTTPS = ext.UpstreamHTTPS
+		arRec.IPFilterMode = ext.IPFilter
+		if len(ext.GeoTargets) > 0 && (arRec.Type == "A" || arRec.Type == "AAAA") {
+			value = ext.GeoTargets
+		}
 	}
+
+	if arRec.Type == "A" || arRec.Type == "AAAA" {
+		switch v := value.(type) {
+		case []ARecordValue:
+			// already the right shape
+		case ARecordValue:
+			value = []ARecordValue{v}
+		default:
+			// A generic libdns.RR carrying a bare "A"/"AAAA" Data string.
+			value = []ARecordValue{{IP: rr.Data}}
+		}
+	}
+
+	raw, err := encodeValue(value)
+	if err != nil {
+		return arDNSRecord{}, fmt.Errorf("encoding %s value: %w", arRec.Type, err)
+	}
+	arRec.Value = raw
+
 	return arRec, nil
 }
 
@@ -6,6 +6,8 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
+	"net/http"
+	"strings"
 	"sync"
 
 	"github.com/libdns/libdns"
@@ -16,18 +18,82 @@ type Provider struct {
 	// AuthAPIKey is the API token for ArvanCloud.
 	// It can be obtained from the ArvanCloud user panel.
 	AuthAPIKey string `json:"auth_api_key,omitempty"`
+
+	// AutoDetectZone lets callers pass any FQDN as the zone (e.g.
+	// "foo.bar.example.com") and have the Provider discover the actual
+	// ArvanCloud-registered domain ("example.com") via GET /domains,
+	// using the longest matching suffix. Off by default so existing
+	// callers that already pass the exact registered zone keep working
+	// unchanged.
+	AutoDetectZone bool `json:"auto_detect_zone,omitempty"`
+
+	// HTTPClient is used to make requests to the ArvanCloud API. If nil,
+	// a client with a 20s timeout is used.
+	HTTPClient *http.Client `json:"-"`
+
+	// UserAgent is sent as the User-Agent header on every request. If
+	// empty, a default identifying this library is used.
+	UserAgent string `json:"user_agent,omitempty"`
+
+	// Concurrency caps how many (name, type) groups SetRecords processes
+	// in parallel. Values less than 1 are treated as 1 (sequential).
+	Concurrency int `json:"concurrency,omitempty"`
+
 	client *client
 	mu     sync.Mutex
+
+	// zoneCache maps a normalized requested FQDN to its resolved
+	// ArvanCloud zone, populated lazily when AutoDetectZone is enabled.
+	zoneCache map[string]string
+}
+
+// resolveZone returns the ArvanCloud-registered zone to use for API calls.
+// When AutoDetectZone is disabled it returns zone unchanged. Otherwise it
+// resolves zone (which may be any FQDN within the registered domain) via
+// client.findHostedZone, caching the result by normalized FQDN so repeated
+// calls for the same name don't re-hit the /domains endpoint.
+func (p *Provider) resolveZone(ctx context.Context, zone string) (string, error) {
+	if !p.AutoDetectZone {
+		return zone, nil
+	}
+
+	key := strings.ToLower(strings.TrimSuffix(zone, "."))
+
+	p.mu.Lock()
+	if cached, ok := p.zoneCache[key]; ok {
+		p.mu.Unlock()
+		return cached, nil
+	}
+	p.mu.Unlock()
+
+	resolved, err := p.client.findHostedZone(ctx, zone)
+	if err != nil {
+		return "", err
+	}
+
+	p.mu.Lock()
+	if p.zoneCache == nil {
+		p.zoneCache = make(map[string]string)
+	}
+	p.zoneCache[key] = resolved
+	p.mu.Unlock()
+
+	return resolved, nil
 }
 
 // GetRecords lists all the records in the zone.
 func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record, error) {
 	p.mu.Lock()
 	if p.client == nil {
-		p.client = newClient(p.AuthAPIKey)
+		p.client = newClient(p.AuthAPIKey, p.HTTPClient, p.UserAgent)
 	}
 	p.mu.Unlock()
 
+	zone, err := p.resolveZone(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
 	arvanRecords, err := p.client.getRecords(ctx, zone)
 	if err != nil {
 		return nil, err
@@ -35,11 +101,11 @@ func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record
 
 	var records []libdns.Record
 	for _, ar := range arvanRecords {
-		libRecord, err := ar.toLibDNSRecord(zone)
+		libRecords, err := ar.toLibDNSRecords(zone)
 		if err != nil {
 			return nil, err
 		}
-		records = append(records, libRecord)
+		records = append(records, libRecords...)
 	}
 	return records, nil
 }
@@ -48,10 +114,15 @@ func (p *Provider) GetRecords(ctx context.Context, zone string) ([]libdns.Record
 func (p *Provider) AppendRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
 	p.mu.Lock()
 	if p.client == nil {
-		p.client = newClient(p.AuthAPIKey)
+		p.client = newClient(p.AuthAPIKey, p.HTTPClient, p.UserAgent)
 	}
 	p.mu.Unlock()
 
+	zone, err := p.resolveZone(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
 	var addedRecords []libdns.Record
 	for _, r := range records {
 		
@@ -69,57 +140,19 @@ func (p *Provider) AppendRecords(ctx context.Context, zone string, records []lib
 	return addedRecords, nil
 }
 
-// SetRecords sets the records in the zone, either by updating existing records or creating new ones.
-// It returns the updated records.
-func (p *Provider) SetRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
-	// For simplicity and correctness with Arvan's array model, we delete existing records
-	// for the names/types provided and then append the new ones.
-	// This ensures the state matches exactly what is requested.
-
-	// 1. Find existing records for these names/types
-	// 2. Delete them
-	// 3. Append new ones
-
-	// Optimization: We can just call DeleteRecords then AppendRecords,
-	// but DeleteRecords requires exact value matching usually.
-	// Here we want to overwrite *all* records for a given Name+Type.
-
+// DeleteRecords deletes the specified records from the zone. It returns the records that were deleted.
+func (p *Provider) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
 	p.mu.Lock()
 	if p.client == nil {
-		p.client = newClient(p.AuthAPIKey)
+		p.client = newClient(p.AuthAPIKey, p.HTTPClient, p.UserAgent)
 	}
 	p.mu.Unlock()
 
-	existingRecords, err := p.client.getRecords(ctx, zone)
+	zone, err := p.resolveZone(ctx, zone)
 	if err != nil {
 		return nil, err
 	}
 
-	for _, r := range records {
-		existing := p.findExistingRecord(existingRecords, r.RR().Name, r.RR().Type, zone)
-		if existing != nil {
-			_,err := p.client.deleteRecord(ctx, zone, existing.ID)
-			if err != nil {
-				return nil, err
-			}
-			// Remove from local cache to avoid trying to delete again if multiple input records match same existing set
-			// (Though findExistingRecord returns a pointer, removing from slice is harder,
-			// but since we loop inputs, we might hit same ID twice.
-			// Arvan API might 404 on second delete, which we should ignore or handle.)
-		}
-	}
-
-	return p.AppendRecords(ctx, zone, records)
-}
-
-// DeleteRecords deletes the specified records from the zone. It returns the records that were deleted.
-func (p *Provider) DeleteRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
-	p.mu.Lock()
-	if p.client == nil {
-		p.client = newClient(p.AuthAPIKey)
-	}
-	p.mu.Unlock()
-
 	existingRecords, err := p.client.getRecords(ctx, zone)
 	if err != nil {
 		return nil, err
@@ -137,8 +170,7 @@ func (p *Provider) DeleteRecords(ctx context.Context, zone string, records []lib
 		if r.RR().Type == "A" || r.RR().Type == "AAAA" {
 			// Handle array removal
 			var currentVals []ARecordValue
-			b, _ := json.Marshal(existing.Value)
-			_ = json.Unmarshal(b, &currentVals)
+			_ = json.Unmarshal(existing.Value, &currentVals)
 
 			var newVals []ARecordValue
 			found := false
@@ -163,7 +195,10 @@ func (p *Provider) DeleteRecords(ctx context.Context, zone string, records []lib
 					}
 				} else {
 					// Update with remaining values
-					existing.Value = newVals
+					existing.Value, err = encodeValue(newVals)
+					if err != nil {
+						return nil, err
+					}
 					result, err := p.client.updateRecord(ctx, zone, existing.ID, *existing)
 					if err != nil {
 						return nil, err
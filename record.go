@@ -0,0 +1,40 @@
+package arvancloud
+
+import "github.com/libdns/libdns"
+
+// Record wraps a libdns.Record with ArvanCloud-specific extensions that have
+// no equivalent in the standard libdns record types: CDN ("cloud") proxying,
+// custom upstream HTTPS behavior, IP filtering/load-balancing, and per-IP
+// geo/weight/port targeting for A/AAAA records. Pass a Record to
+// AppendRecords or SetRecords exactly like any other libdns.Record to manage
+// these fields through the same API.
+type Record struct {
+	libdns.Record
+
+	// Proxied enables ArvanCloud's CDN ("cloud") proxying for this record.
+	Proxied bool
+
+	// UpstreamHTTPS controls how ArvanCloud talks to the origin over HTTPS
+	// when Proxied is enabled.
+	UpstreamHTTPS string
+
+	// IPFilter configures ArvanCloud's IP filtering/load-balancing mode.
+	IPFilter *IPFilter
+
+	// GeoTargets, when set on an A/AAAA record, supplies the full set of
+	// IP values (with per-IP weight/port/country) ArvanCloud should serve
+	// for this name, overriding the single IP on the embedded record.
+	GeoTargets []ARecordValue
+}
+
+// WrapLibDNS attaches ArvanCloud-specific extensions to an existing
+// libdns.Record. The returned Record can be passed to AppendRecords or
+// SetRecords like any other libdns.Record.
+func WrapLibDNS(r libdns.Record) Record {
+	return Record{Record: r}
+}
+
+// RR returns the underlying record's resource record representation.
+func (r Record) RR() libdns.RR {
+	return r.Record.RR()
+}
@@ -0,0 +1,238 @@
+package arvancloud
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/libdns/libdns"
+	"golang.org/x/sync/errgroup"
+)
+
+// recordGroup collects the input records that share a (name, type) pair,
+// which is the granularity ArvanCloud (and the libdns contract) treats
+// records at.
+type recordGroup struct {
+	Name    string
+	Type    string
+	Records []libdns.Record
+}
+
+// groupByNameType buckets records by their (name, type), preserving the
+// order groups are first seen in.
+func groupByNameType(records []libdns.Record) []recordGroup {
+	index := make(map[string]int, len(records))
+	var groups []recordGroup
+
+	for _, r := range records {
+		rr := r.RR()
+		key := strings.ToLower(rr.Name) + "|" + strings.ToUpper(rr.Type)
+		if i, ok := index[key]; ok {
+			groups[i].Records = append(groups[i].Records, r)
+			continue
+		}
+		index[key] = len(groups)
+		groups = append(groups, recordGroup{
+			Name:    rr.Name,
+			Type:    rr.Type,
+			Records: []libdns.Record{r},
+		})
+	}
+
+	return groups
+}
+
+// SetRecords sets the records in the zone, either by updating existing
+// records or creating new ones. It returns the updated records.
+//
+// Inputs are grouped by (name, type) and each group is replaced in a
+// single pass: A/AAAA groups are written as one multi-value record (a PUT
+// over the first existing record, or a POST when there is none, with any
+// further existing records for the name deleted), other types are
+// replaced with one DELETE-then-POST
+// pass. If a group fails partway through, its previous ArvanCloud state is
+// restored from the cached original records before the error is returned.
+// Independent groups are processed concurrently, bounded by
+// Provider.Concurrency (default 1, i.e. sequential).
+func (p *Provider) SetRecords(ctx context.Context, zone string, records []libdns.Record) ([]libdns.Record, error) {
+	p.mu.Lock()
+	if p.client == nil {
+		p.client = newClient(p.AuthAPIKey, p.HTTPClient, p.UserAgent)
+	}
+	p.mu.Unlock()
+
+	zone, err := p.resolveZone(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	existingRecords, err := p.client.getRecords(ctx, zone)
+	if err != nil {
+		return nil, err
+	}
+
+	groups := groupByNameType(records)
+
+	concurrency := p.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(concurrency)
+
+	set := make([][]libdns.Record, len(groups))
+	for i, grp := range groups {
+		i, grp := i, grp
+		g.Go(func() error {
+			grpRecords, err := p.setGroup(gctx, zone, grp, existingRecords)
+			if err != nil {
+				return err
+			}
+			set[i] = grpRecords
+			return nil
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	var setRecords []libdns.Record
+	for _, grpRecords := range set {
+		setRecords = append(setRecords, grpRecords...)
+	}
+	return setRecords, nil
+}
+
+// setGroup replaces every ArvanCloud record matching grp's (name, type)
+// with grp.Records, restoring the previous state if the replacement fails
+// partway through.
+func (p *Provider) setGroup(ctx context.Context, zone string, grp recordGroup, existing []arDNSRecord) ([]libdns.Record, error) {
+	groupName := libdns.AbsoluteName(grp.Name, zone)
+
+	var previous []arDNSRecord
+	for _, ar := range existing {
+		if strings.EqualFold(ar.Type, grp.Type) && strings.EqualFold(arvanAbsoluteName(ar.Name, zone), groupName) {
+			previous = append(previous, ar)
+		}
+	}
+
+	set, err := p.replaceGroup(ctx, zone, grp, previous)
+	if err != nil {
+		if restoreErr := p.restoreGroup(ctx, zone, previous); restoreErr != nil {
+			return nil, fmt.Errorf("setting %s %q failed (%w) and restoring previous state also failed: %v", grp.Type, grp.Name, err, restoreErr)
+		}
+		return nil, err
+	}
+	return set, nil
+}
+
+// replaceGroup issues the API calls that make ArvanCloud's records for
+// grp's (name, type) match grp.Records exactly.
+func (p *Provider) replaceGroup(ctx context.Context, zone string, grp recordGroup, previous []arDNSRecord) ([]libdns.Record, error) {
+	if grp.Type == "A" || grp.Type == "AAAA" {
+		return p.replaceAddressGroup(ctx, zone, grp, previous)
+	}
+
+	for _, old := range previous {
+		if _, err := p.client.deleteRecord(ctx, zone, old.ID); err != nil {
+			return nil, err
+		}
+	}
+
+	var set []libdns.Record
+	var createdIDs []string
+	for _, r := range grp.Records {
+		created, err := p.client.createRecord(ctx, zone, r)
+		if err != nil {
+			// Remove what this pass already created, so that restoring
+			// the previous records leaves no new ones behind.
+			for _, id := range createdIDs {
+				_, _ = p.client.deleteRecord(ctx, zone, id)
+			}
+			return nil, err
+		}
+		createdIDs = append(createdIDs, created.ID)
+		libRecord, err := created.toLibDNSRecord(zone)
+		if err != nil {
+			return nil, fmt.Errorf("parsing Arvancloud DNS record %+v: %v", r, err)
+		}
+		set = append(set, libRecord)
+	}
+	return set, nil
+}
+
+// replaceAddressGroup coalesces grp.Records into a single ArvanCloud
+// A/AAAA record carrying one value per input record, and writes it with a
+// single PUT over the first previously-existing record (or a POST when
+// there is none) so the whole name's address list changes in one call
+// instead of via per-IP delete/create calls. Any further previously-existing
+// records for the name are deleted afterwards.
+func (p *Provider) replaceAddressGroup(ctx context.Context, zone string, grp recordGroup, previous []arDNSRecord) ([]libdns.Record, error) {
+	arRec, err := arvancloudRecord(grp.Records[0])
+	if err != nil {
+		return nil, err
+	}
+
+	var vals []ARecordValue
+	for _, r := range grp.Records {
+		rec, err := arvancloudRecord(r)
+		if err != nil {
+			return nil, err
+		}
+		var v []ARecordValue
+		if err := decodeValue(rec.Value, &v); err != nil {
+			return nil, err
+		}
+		vals = append(vals, v...)
+	}
+	raw, err := encodeValue(vals)
+	if err != nil {
+		return nil, err
+	}
+	arRec.Value = raw
+
+	var result arDNSRecord
+	if len(previous) > 0 {
+		arRec.ID = previous[0].ID
+		result, err = p.client.updateRecord(ctx, zone, arRec.ID, arRec)
+		if err != nil {
+			return nil, err
+		}
+		for _, old := range previous[1:] {
+			if _, err := p.client.deleteRecord(ctx, zone, old.ID); err != nil {
+				return nil, err
+			}
+		}
+	} else {
+		result, err = p.client.createArvanRecord(ctx, zone, arRec)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	records, err := result.toLibDNSRecords(zone)
+	if err != nil {
+		return nil, fmt.Errorf("parsing Arvancloud DNS record %+v: %v", result, err)
+	}
+	return records, nil
+}
+
+// restoreGroup attempts to put previous's records back exactly as they
+// were, used when a mid-group write fails after already mutating some of
+// ArvanCloud's state. An update is tried first (the record may still
+// exist); if that fails, the record is re-created from the cached copy.
+func (p *Provider) restoreGroup(ctx context.Context, zone string, previous []arDNSRecord) error {
+	for _, old := range previous {
+		if _, err := p.client.updateRecord(ctx, zone, old.ID, old); err == nil {
+			continue
+		}
+		recreated := old
+		recreated.ID = ""
+		if _, err := p.client.createArvanRecord(ctx, zone, recreated); err != nil {
+			return err
+		}
+	}
+	return nil
+}